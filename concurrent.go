@@ -0,0 +1,541 @@
+package multimap
+
+import (
+	"sync/atomic"
+)
+
+// trieBits is the number of hash bits consumed at each level of the trie,
+// giving each interior node a fan-out of 1<<trieBits.
+const trieBits = 4
+
+// trieFanout is the number of children of each interior node.
+const trieFanout = 1 << trieBits
+
+// trieMaxDepth is the number of levels needed to consume a full 64-bit
+// hash. Beyond this depth there are no more hash bits left to branch on,
+// so colliding keys are kept in a chained leaf list instead.
+const trieMaxDepth = 64 / trieBits
+
+// ConcurrentMultiMap is a lock-free, thread-safe map that allows multiple
+// values per key, implemented as a hash-array-mapped trie in the style of
+// Go's internal/concurrent.HashTrieMap. Reads never block writers: lookups
+// walk the trie following atomic.Pointer loads, and writers install new
+// nodes with compare-and-swap instead of taking a lock.
+type ConcurrentMultiMap[K comparable, V any] struct {
+	root       *trieInterior[K, V]
+	hashFunc   func(K) uint64
+	equalsFunc func(a, b V) bool
+}
+
+// trieInterior is an interior node: a fixed fan-out array of child slots,
+// each swapped atomically.
+type trieInterior[K comparable, V any] struct {
+	children [trieFanout]atomic.Pointer[trieSlot[K, V]]
+}
+
+// trieSlot is the content of one child slot: either a leaf (possibly
+// chained, for full hash collisions) or a deeper interior node. A slot is
+// never mutated in place; writers install a new *trieSlot via CAS.
+type trieSlot[K comparable, V any] struct {
+	leaf     *trieLeaf[K, V]
+	interior *trieInterior[K, V]
+}
+
+// trieLeaf holds one key and its values. values is swapped atomically so
+// that Add can append to a lone leaf's values without replacing its slot;
+// RemoveValue, Store and CompareAndDelete instead build new leaves and
+// install them with a CAS on the slot. next chains to another leaf that hashed identically across all
+// trieMaxDepth levels (only possible with a pathological hash function).
+type trieLeaf[K comparable, V any] struct {
+	key    K
+	values atomic.Pointer[[]V]
+	next   *trieLeaf[K, V]
+}
+
+// NewConcurrent creates a new ConcurrentMultiMap using hashFunc to place
+// keys in the trie and equalsFunc to determine value equality.
+func NewConcurrent[K comparable, V any](hashFunc func(K) uint64, equalsFunc func(a, b V) bool) *ConcurrentMultiMap[K, V] {
+	return &ConcurrentMultiMap[K, V]{
+		root:       &trieInterior[K, V]{},
+		hashFunc:   hashFunc,
+		equalsFunc: equalsFunc,
+	}
+}
+
+func trieChunk(hash uint64, depth int) int {
+	return int((hash >> uint(depth*trieBits)) & (trieFanout - 1))
+}
+
+func copyValues[V any](p *[]V) []V {
+	if p == nil {
+		return nil
+	}
+	cp := make([]V, len(*p))
+	copy(cp, *p)
+	return cp
+}
+
+// Load returns the values stored for key and true, or nil and false if the
+// key is absent. It never blocks on a writer.
+func (m *ConcurrentMultiMap[K, V]) Load(key K) ([]V, bool) {
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; depth < trieMaxDepth; depth++ {
+		s := cur.children[trieChunk(hash, depth)].Load()
+		if s == nil {
+			return nil, false
+		}
+		if s.leaf != nil {
+			for n := s.leaf; n != nil; n = n.next {
+				if n.key == key {
+					return copyValues(n.values.Load()), true
+				}
+			}
+			return nil, false
+		}
+		cur = s.interior
+	}
+	return nil, false
+}
+
+// Get returns a copy of the values for the given key, or an empty slice if
+// the key is absent.
+func (m *ConcurrentMultiMap[K, V]) Get(key K) []V {
+	values, _ := m.Load(key)
+	return values
+}
+
+// Has returns true if the given value exists for the key (by equalsFunc).
+func (m *ConcurrentMultiMap[K, V]) Has(key K, value V) bool {
+	values, ok := m.Load(key)
+	if !ok {
+		return false
+	}
+	for _, v := range values {
+		if m.equalsFunc(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasKey returns true if the key exists in the map.
+func (m *ConcurrentMultiMap[K, V]) HasKey(key K) bool {
+	_, ok := m.Load(key)
+	return ok
+}
+
+// chainWith returns a new chain equal to chain but with value added under
+// key: if key is already present in the chain, value is appended to its
+// values (deduped by eq); otherwise a new leaf is prepended.
+func chainWith[K comparable, V any](chain *trieLeaf[K, V], key K, value V, eq func(a, b V) bool) *trieLeaf[K, V] {
+	var rebuilt *trieLeaf[K, V]
+	matched := false
+	for n := chain; n != nil; n = n.next {
+		values := copyValues(n.values.Load())
+		if n.key == key {
+			matched = true
+			dup := false
+			for _, v := range values {
+				if eq(v, value) {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				values = append(values, value)
+			}
+		}
+		nl := &trieLeaf[K, V]{key: n.key}
+		nl.values.Store(&values)
+		nl.next = rebuilt
+		rebuilt = nl
+	}
+	if !matched {
+		nl := &trieLeaf[K, V]{key: key}
+		nl.values.Store(&[]V{value})
+		nl.next = rebuilt
+		rebuilt = nl
+	}
+	return rebuilt
+}
+
+// Add inserts a value for the given key if it does not already exist (by
+// equalsFunc), retrying its CAS until it wins a race against concurrent
+// writers. A failed CAS re-reads and retries the same slot (depth stays
+// put); only a successful expansion into a child interior node advances
+// depth. Using the loop's depth++ to "retry" instead would silently retry
+// against a different, unrelated slot and drop the write.
+func (m *ConcurrentMultiMap[K, V]) Add(key K, value V) {
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; ; depth++ {
+		idx := trieChunk(hash, depth)
+		slot := &cur.children[idx]
+		old := slot.Load()
+
+		if old == nil {
+			nl := &trieLeaf[K, V]{key: key}
+			nl.values.Store(&[]V{value})
+			if slot.CompareAndSwap(nil, &trieSlot[K, V]{leaf: nl}) {
+				return
+			}
+			depth--
+			continue
+		}
+
+		if old.leaf != nil {
+			if depth >= trieMaxDepth-1 {
+				newChain := chainWith(old.leaf, key, value, m.equalsFunc)
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: newChain}) {
+					return
+				}
+				depth--
+				continue
+			}
+			if old.leaf.next == nil && old.leaf.key == key {
+				for {
+					ov := old.leaf.values.Load()
+					dup := false
+					for _, v := range *ov {
+						if m.equalsFunc(v, value) {
+							dup = true
+							break
+						}
+					}
+					if dup {
+						return
+					}
+					nv := make([]V, len(*ov)+1)
+					copy(nv, *ov)
+					nv[len(*ov)] = value
+					if old.leaf.values.CompareAndSwap(ov, &nv) {
+						return
+					}
+				}
+			}
+			if old.leaf.next == nil && old.leaf.key != key {
+				child := &trieInterior[K, V]{}
+				moveIdx := trieChunk(m.hashFunc(old.leaf.key), depth+1)
+				child.children[moveIdx].Store(&trieSlot[K, V]{leaf: old.leaf})
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{interior: child}) {
+					cur = child
+					continue
+				}
+				depth--
+				continue
+			}
+			// Chained leaf encountered before max depth: fall through to
+			// chain handling, which also covers the non-colliding case.
+			newChain := chainWith(old.leaf, key, value, m.equalsFunc)
+			if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: newChain}) {
+				return
+			}
+			depth--
+			continue
+		}
+
+		cur = old.interior
+	}
+}
+
+// RemoveValue removes a specific value for the given key (by equalsFunc).
+// If no values remain for the key, the key is removed.
+func (m *ConcurrentMultiMap[K, V]) RemoveValue(key K, value V) {
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; depth < trieMaxDepth; depth++ {
+		idx := trieChunk(hash, depth)
+		slot := &cur.children[idx]
+		old := slot.Load()
+		if old == nil {
+			return
+		}
+		if old.leaf != nil {
+			var rebuilt *trieLeaf[K, V]
+			for n := old.leaf; n != nil; n = n.next {
+				if n.key == key {
+					values := copyValues(n.values.Load())
+					kept := values[:0:0]
+					for _, v := range values {
+						if !m.equalsFunc(v, value) {
+							kept = append(kept, v)
+						}
+					}
+					if len(kept) == 0 {
+						continue
+					}
+					nl := &trieLeaf[K, V]{key: n.key}
+					nl.values.Store(&kept)
+					nl.next = rebuilt
+					rebuilt = nl
+					continue
+				}
+				nl := &trieLeaf[K, V]{key: n.key}
+				nl.values.Store(n.values.Load())
+				nl.next = rebuilt
+				rebuilt = nl
+			}
+			var newSlot *trieSlot[K, V]
+			if rebuilt != nil {
+				newSlot = &trieSlot[K, V]{leaf: rebuilt}
+			}
+			if slot.CompareAndSwap(old, newSlot) {
+				return
+			}
+			depth-- // retry this level against the new state
+			continue
+		}
+		cur = old.interior
+	}
+}
+
+// Store replaces the entire value list for key, overwriting whatever was
+// there before. As in Add, a failed CAS retries the same slot (depth--);
+// only a successful expansion into a child interior node advances depth.
+func (m *ConcurrentMultiMap[K, V]) Store(key K, values []V) {
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; ; depth++ {
+		idx := trieChunk(hash, depth)
+		slot := &cur.children[idx]
+		old := slot.Load()
+
+		if old == nil {
+			nl := &trieLeaf[K, V]{key: key}
+			nl.values.Store(&values)
+			if slot.CompareAndSwap(nil, &trieSlot[K, V]{leaf: nl}) {
+				return
+			}
+			depth--
+			continue
+		}
+
+		if old.leaf != nil {
+			if old.leaf.next == nil && old.leaf.key == key {
+				nl := &trieLeaf[K, V]{key: key}
+				nl.values.Store(&values)
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: nl}) {
+					return
+				}
+				depth--
+				continue
+			}
+			if depth >= trieMaxDepth-1 {
+				newChain := replaceInChain(old.leaf, key, values)
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: newChain}) {
+					return
+				}
+				depth--
+				continue
+			}
+			if containsKey(old.leaf, key) {
+				newChain := replaceInChain(old.leaf, key, values)
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: newChain}) {
+					return
+				}
+				depth--
+				continue
+			}
+			child := &trieInterior[K, V]{}
+			moveIdx := trieChunk(m.hashFunc(old.leaf.key), depth+1)
+			child.children[moveIdx].Store(old)
+			if slot.CompareAndSwap(old, &trieSlot[K, V]{interior: child}) {
+				cur = child
+				continue
+			}
+			depth--
+			continue
+		}
+
+		cur = old.interior
+	}
+}
+
+func containsKey[K comparable, V any](chain *trieLeaf[K, V], key K) bool {
+	for n := chain; n != nil; n = n.next {
+		if n.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceInChain[K comparable, V any](chain *trieLeaf[K, V], key K, values []V) *trieLeaf[K, V] {
+	var rebuilt *trieLeaf[K, V]
+	matched := false
+	for n := chain; n != nil; n = n.next {
+		var nl *trieLeaf[K, V]
+		if n.key == key {
+			nl = &trieLeaf[K, V]{key: key}
+			nl.values.Store(&values)
+			matched = true
+		} else {
+			nl = &trieLeaf[K, V]{key: n.key}
+			nl.values.Store(n.values.Load())
+		}
+		nl.next = rebuilt
+		rebuilt = nl
+	}
+	if !matched {
+		nl := &trieLeaf[K, V]{key: key}
+		nl.values.Store(&values)
+		nl.next = rebuilt
+		rebuilt = nl
+	}
+	return rebuilt
+}
+
+// LoadOrStore returns the existing values for key if present; otherwise it
+// stores values for key and returns them. The loaded result reports which
+// case occurred. As in Add, a failed CAS retries the same slot (depth--);
+// only a successful expansion into a child interior node advances depth.
+func (m *ConcurrentMultiMap[K, V]) LoadOrStore(key K, values []V) (actual []V, loaded bool) {
+	if existing, ok := m.Load(key); ok {
+		return existing, true
+	}
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; ; depth++ {
+		idx := trieChunk(hash, depth)
+		slot := &cur.children[idx]
+		old := slot.Load()
+
+		if old == nil {
+			nl := &trieLeaf[K, V]{key: key}
+			nl.values.Store(&values)
+			if slot.CompareAndSwap(nil, &trieSlot[K, V]{leaf: nl}) {
+				return values, false
+			}
+			depth--
+			continue
+		}
+
+		if old.leaf != nil {
+			if existing, ok := loadFromChain(old.leaf, key); ok {
+				return existing, true
+			}
+			if depth >= trieMaxDepth-1 {
+				newChain := replaceInChain(old.leaf, key, values)
+				if slot.CompareAndSwap(old, &trieSlot[K, V]{leaf: newChain}) {
+					return values, false
+				}
+				depth--
+				continue
+			}
+			child := &trieInterior[K, V]{}
+			moveIdx := trieChunk(m.hashFunc(old.leaf.key), depth+1)
+			child.children[moveIdx].Store(old)
+			if slot.CompareAndSwap(old, &trieSlot[K, V]{interior: child}) {
+				cur = child
+				continue
+			}
+			depth--
+			continue
+		}
+
+		cur = old.interior
+	}
+}
+
+func loadFromChain[K comparable, V any](chain *trieLeaf[K, V], key K) ([]V, bool) {
+	for n := chain; n != nil; n = n.next {
+		if n.key == key {
+			return copyValues(n.values.Load()), true
+		}
+	}
+	return nil, false
+}
+
+// CompareAndDelete deletes the entry for key if its current value list
+// equals old (same length, each element matched by equalsFunc in order).
+// It reports whether the delete took place.
+func (m *ConcurrentMultiMap[K, V]) CompareAndDelete(key K, old []V) bool {
+	hash := m.hashFunc(key)
+	cur := m.root
+	for depth := 0; depth < trieMaxDepth; depth++ {
+		idx := trieChunk(hash, depth)
+		slot := &cur.children[idx]
+		s := slot.Load()
+		if s == nil {
+			return false
+		}
+		if s.leaf != nil {
+			current, ok := loadFromChain(s.leaf, key)
+			if !ok || !valuesEqual(current, old, m.equalsFunc) {
+				return false
+			}
+			newChain := removeFromChain(s.leaf, key)
+			var newSlot *trieSlot[K, V]
+			if newChain != nil {
+				newSlot = &trieSlot[K, V]{leaf: newChain}
+			}
+			return slot.CompareAndSwap(s, newSlot)
+		}
+		cur = s.interior
+	}
+	return false
+}
+
+func valuesEqual[V any](a, b []V, eq func(a, b V) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !eq(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func removeFromChain[K comparable, V any](chain *trieLeaf[K, V], key K) *trieLeaf[K, V] {
+	var rebuilt *trieLeaf[K, V]
+	for n := chain; n != nil; n = n.next {
+		if n.key == key {
+			continue
+		}
+		nl := &trieLeaf[K, V]{key: n.key}
+		nl.values.Store(n.values.Load())
+		nl.next = rebuilt
+		rebuilt = nl
+	}
+	return rebuilt
+}
+
+// Range calls f for every key and its values. Iteration order is
+// unspecified. If f returns false, Range stops early.
+func (m *ConcurrentMultiMap[K, V]) Range(f func(K, []V) bool) {
+	rangeInterior(m.root, f)
+}
+
+func rangeInterior[K comparable, V any](n *trieInterior[K, V], f func(K, []V) bool) bool {
+	for i := range n.children {
+		s := n.children[i].Load()
+		if s == nil {
+			continue
+		}
+		if s.leaf != nil {
+			for leaf := s.leaf; leaf != nil; leaf = leaf.next {
+				if !f(leaf.key, copyValues(leaf.values.Load())) {
+					return false
+				}
+			}
+			continue
+		}
+		if !rangeInterior(s.interior, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForEach calls f for each key-value pair, in unspecified order.
+func (m *ConcurrentMultiMap[K, V]) ForEach(f func(K, V)) {
+	m.Range(func(k K, values []V) bool {
+		for _, v := range values {
+			f(k, v)
+		}
+		return true
+	})
+}
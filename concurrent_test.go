@@ -0,0 +1,248 @@
+package multimap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentAddAndGet(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("a", 1) // duplicate, should not be added
+
+	vals := m.Get("a")
+	sort.Ints(vals)
+	if !reflect.DeepEqual(vals, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", vals)
+	}
+}
+
+func TestConcurrentHasAndHasKey(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Add("a", 1)
+	if !m.Has("a", 1) {
+		t.Errorf("expected Has(a,1)")
+	}
+	if m.Has("a", 2) {
+		t.Errorf("did not expect Has(a,2)")
+	}
+	if !m.HasKey("a") || m.HasKey("b") {
+		t.Errorf("unexpected HasKey results")
+	}
+}
+
+func TestConcurrentRemoveValue(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.RemoveValue("a", 1)
+	if !reflect.DeepEqual(m.Get("a"), []int{2}) {
+		t.Errorf("expected [2], got %v", m.Get("a"))
+	}
+	m.RemoveValue("a", 2)
+	if m.HasKey("a") {
+		t.Errorf("expected key 'a' removed after last value")
+	}
+}
+
+func TestConcurrentStoreLoadOrStoreCompareAndDelete(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Store("a", []int{1, 2})
+	vals, ok := m.Load("a")
+	if !ok || !reflect.DeepEqual(vals, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v ok=%v", vals, ok)
+	}
+
+	actual, loaded := m.LoadOrStore("a", []int{9})
+	if !loaded || !reflect.DeepEqual(actual, []int{1, 2}) {
+		t.Errorf("expected existing [1 2] on LoadOrStore, got %v loaded=%v", actual, loaded)
+	}
+	actual, loaded = m.LoadOrStore("b", []int{9})
+	if loaded || !reflect.DeepEqual(actual, []int{9}) {
+		t.Errorf("expected stored [9] on LoadOrStore for new key, got %v loaded=%v", actual, loaded)
+	}
+
+	if m.CompareAndDelete("a", []int{9}) {
+		t.Errorf("expected CompareAndDelete to fail on mismatched old value")
+	}
+	if !m.CompareAndDelete("a", []int{1, 2}) {
+		t.Errorf("expected CompareAndDelete to succeed on matching old value")
+	}
+	if m.HasKey("a") {
+		t.Errorf("expected key 'a' removed after CompareAndDelete")
+	}
+}
+
+func TestConcurrentRange(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Add("c", 3)
+
+	seen := map[string]int{}
+	m.Range(func(k string, values []int) bool {
+		seen[k] = len(values)
+		return true
+	})
+	if len(seen) != 3 {
+		t.Errorf("expected 3 keys, got %v", seen)
+	}
+
+	count := 0
+	m.Range(func(k string, values []int) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("expected Range to stop after first entry, got %d calls", count)
+	}
+}
+
+func TestConcurrentForEach(t *testing.T) {
+	m := NewConcurrent[string, int](HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	total := 0
+	m.ForEach(func(k string, v int) {
+		total += v
+	})
+	if total != 3 {
+		t.Errorf("expected total 3, got %d", total)
+	}
+}
+
+func TestConcurrentConcurrentAdds(t *testing.T) {
+	m := NewConcurrent[int, int](HashInt, intEquals)
+	const goroutines = 32
+	const perGoroutine = 100
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				m.Add(g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 0; g < goroutines; g++ {
+		if len(m.Get(g)) != perGoroutine {
+			t.Errorf("key %d: expected %d values, got %d", g, perGoroutine, len(m.Get(g)))
+		}
+	}
+}
+
+// TestConcurrentAddNewKeysRaceSameSlot is a regression test for a bug where
+// a failed top-level CAS in Add used the loop's depth++ to "retry," which
+// actually moved on to an unrelated child slot instead of re-reading the
+// slot that just lost the race. With enough goroutines inserting distinct
+// brand-new keys, many of them land in the same initially-empty slot at
+// depth 0 and lose the race there; the bug silently dropped those writes.
+func TestConcurrentAddNewKeysRaceSameSlot(t *testing.T) {
+	m := NewConcurrent[int, int](HashInt, intEquals)
+	const goroutines = 64
+	const keysPerGoroutine = 50
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < keysPerGoroutine; i++ {
+				m.Add(g*keysPerGoroutine+i, 1)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 0; g < goroutines; g++ {
+		for i := 0; i < keysPerGoroutine; i++ {
+			key := g*keysPerGoroutine + i
+			if !reflect.DeepEqual(m.Get(key), []int{1}) {
+				t.Errorf("key %d: expected [1], got %v", key, m.Get(key))
+			}
+		}
+	}
+}
+
+// badHashKey is a key type hashed by badHash, which always returns 0
+// regardless of its payload, forcing every key into the same trie slot all the way down to
+// trieMaxDepth and exercising the chained-leaf collision path.
+type badHashKey struct {
+	id int
+}
+
+func badHash(k badHashKey) uint64 {
+	return 0
+}
+
+func badHashEquals(a, b int) bool { return a == b }
+
+func TestConcurrentBadHashDeepCollisionChain(t *testing.T) {
+	m := NewConcurrent[badHashKey, int](badHash, badHashEquals)
+	const n = 50
+	for i := 0; i < n; i++ {
+		m.Add(badHashKey{id: i}, i*10)
+	}
+	for i := 0; i < n; i++ {
+		vals := m.Get(badHashKey{id: i})
+		if !reflect.DeepEqual(vals, []int{i * 10}) {
+			t.Errorf("key %d: expected [%d], got %v", i, i*10, vals)
+		}
+	}
+
+	m.RemoveValue(badHashKey{id: n / 2}, (n/2)*10)
+	if m.HasKey(badHashKey{id: n / 2}) {
+		t.Errorf("expected key %d removed from collision chain", n/2)
+	}
+	if !m.HasKey(badHashKey{id: 0}) || !m.HasKey(badHashKey{id: n - 1}) {
+		t.Errorf("expected other chained keys to remain after removal")
+	}
+}
+
+func TestConcurrentBadHashConcurrentAdds(t *testing.T) {
+	m := NewConcurrent[badHashKey, int](badHash, badHashEquals)
+	const goroutines = 16
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			m.Add(badHashKey{id: g}, g)
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 0; g < goroutines; g++ {
+		if !m.Has(badHashKey{id: g}, g) {
+			t.Errorf("expected key %d present after concurrent adds under hash collisions", g)
+		}
+	}
+}
+
+func BenchmarkConcurrentAdd(b *testing.B) {
+	for _, goroutines := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("%dg", goroutines), func(b *testing.B) {
+			m := NewConcurrent[int, int](HashInt, intEquals)
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < b.N/goroutines+1; i++ {
+						m.Add((g*1000+i)%1024, i)
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
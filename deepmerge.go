@@ -0,0 +1,119 @@
+package multimap
+
+import "fmt"
+
+// Mergeable is implemented by values that know how to absorb another value
+// of the same type into themselves, in place.
+type Mergeable[T any] interface {
+	Merge(other T)
+}
+
+// DeepMerge merges src into dst key by key: for a key present in both maps,
+// it calls Merge on dst's existing value (passing src's value as other)
+// instead of appending a duplicate; keys only present in src are added to
+// dst wholesale. src is left unmodified.
+//
+// DeepMerge only has a well-defined notion of "the existing value" to merge
+// into when a key holds exactly one value on each side; if a key present in
+// both maps holds more than one value on either side, DeepMerge returns an
+// error instead of guessing how to pair them up, leaving dst unmodified.
+func DeepMerge[K comparable, V Mergeable[V]](dst, src *MultiMap[K, V]) error {
+	unlock := lockDstSrc(dst, src)
+	defer unlock()
+
+	for k, srcValues := range src.data {
+		dstValues, ok := dst.data[k]
+		if !ok || len(dstValues) == 0 {
+			continue
+		}
+		if len(dstValues) != 1 || len(srcValues) != 1 {
+			return fmt.Errorf("multimap: DeepMerge: key %v has %d value(s) in dst and %d in src; only single-valued keys can be merged", k, len(dstValues), len(srcValues))
+		}
+	}
+
+	for k, srcValues := range src.data {
+		dstValues, ok := dst.data[k]
+		if !ok || len(dstValues) == 0 {
+			copied := make([]V, len(srcValues))
+			copy(copied, srcValues)
+			dst.data[k] = copied
+			continue
+		}
+		dstValues[0].Merge(srcValues[0])
+	}
+	return nil
+}
+
+// DeepMergeAny merges src into dst for the common case where V is any and
+// values may themselves be map[string]any or []any. For each key present in
+// both maps, the existing and incoming values are deep-merged with
+// mergeAnyValue: maps are merged per-key, slices are concatenated, and
+// scalars are replaced by the incoming value. Keys only present in src are
+// added wholesale. This mirrors the behavior used by structured
+// configuration/event payload libraries such as Elastic's MapStr.DeepUpdate.
+//
+// Like DeepMerge, a key present in both maps must hold exactly one value on
+// each side; DeepMergeAny returns an error instead of guessing how to pair
+// up multiple values for the same key, leaving dst unmodified.
+func DeepMergeAny[K comparable](dst, src *MultiMap[K, any]) error {
+	unlock := lockDstSrc(dst, src)
+	defer unlock()
+
+	for k, srcValues := range src.data {
+		dstValues, ok := dst.data[k]
+		if !ok || len(dstValues) == 0 {
+			continue
+		}
+		if len(dstValues) != 1 || len(srcValues) != 1 {
+			return fmt.Errorf("multimap: DeepMergeAny: key %v has %d value(s) in dst and %d in src; only single-valued keys can be merged", k, len(dstValues), len(srcValues))
+		}
+	}
+
+	for k, srcValues := range src.data {
+		dstValues, ok := dst.data[k]
+		if !ok || len(dstValues) == 0 {
+			copied := make([]any, len(srcValues))
+			copy(copied, srcValues)
+			dst.data[k] = copied
+			continue
+		}
+		dstValues[0] = mergeAnyValue(dstValues[0], srcValues[0])
+	}
+	return nil
+}
+
+// mergeAnyValue deep-merges src into dst: matching map[string]any keys
+// recurse, []any slices concatenate, and anything else (including mismatched
+// types) is replaced wholesale by src.
+func mergeAnyValue(dst, src any) any {
+	switch s := src.(type) {
+	case map[string]any:
+		d, ok := dst.(map[string]any)
+		if !ok {
+			return s
+		}
+		merged := make(map[string]any, len(d)+len(s))
+		for k, v := range d {
+			merged[k] = v
+		}
+		for k, sv := range s {
+			if dv, ok := merged[k]; ok {
+				merged[k] = mergeAnyValue(dv, sv)
+			} else {
+				merged[k] = sv
+			}
+		}
+		return merged
+	case []any:
+		d, ok := dst.([]any)
+		if !ok {
+			return s
+		}
+		merged := make([]any, 0, len(d)+len(s))
+		merged = append(merged, d...)
+		merged = append(merged, s...)
+		return merged
+	default:
+		return s
+	}
+}
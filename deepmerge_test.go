@@ -0,0 +1,147 @@
+package multimap
+
+import (
+	"reflect"
+	"testing"
+)
+
+// counter is a minimal Mergeable value used to test DeepMerge.
+type counter struct {
+	total int
+}
+
+func (c *counter) Merge(other *counter) {
+	c.total += other.total
+}
+
+func counterEquals(a, b *counter) bool {
+	return a.total == b.total
+}
+
+func TestDeepMerge(t *testing.T) {
+	dst := New[string, *counter](counterEquals)
+	dst.data["a"] = []*counter{{total: 1}}
+	src := New[string, *counter](counterEquals)
+	src.data["a"] = []*counter{{total: 2}}
+	src.data["b"] = []*counter{{total: 3}}
+
+	if err := DeepMerge(dst, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if dst.Get("a")[0].total != 3 {
+		t.Errorf("expected merged total 3 for key 'a', got %d", dst.Get("a")[0].total)
+	}
+	if dst.Get("b")[0].total != 3 {
+		t.Errorf("expected key 'b' added wholesale with total 3, got %v", dst.Get("b"))
+	}
+	if src.Get("a")[0].total != 2 {
+		t.Errorf("expected src unmodified, got %d", src.Get("a")[0].total)
+	}
+}
+
+// TestDeepMergeMultiValuedKeyErrors is a regression test documenting that
+// DeepMerge refuses to guess how to pair up values when a key present in
+// both maps holds more than one value: pairing dst's values with src's by
+// slice position would silently do the wrong thing (e.g. leaving a dst
+// value untouched whenever src has fewer values for that key than dst),
+// so DeepMerge reports an error and leaves dst unmodified instead.
+func TestDeepMergeMultiValuedKeyErrors(t *testing.T) {
+	dst := New[string, *counter](counterEquals)
+	dst.data["a"] = []*counter{{total: 1}, {total: 2}}
+	src := New[string, *counter](counterEquals)
+	src.data["a"] = []*counter{{total: 10}}
+
+	err := DeepMerge(dst, src)
+	if err == nil {
+		t.Fatal("expected an error for a multi-valued key, got nil")
+	}
+	if dst.Get("a")[0].total != 1 || dst.Get("a")[1].total != 2 {
+		t.Errorf("expected dst left unmodified after error, got %v", dst.Get("a"))
+	}
+}
+
+func TestDeepMergeAnyScalarAndSliceConcat(t *testing.T) {
+	dst := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	dst.data["tags"] = []any{[]any{"a", "b"}}
+	dst.data["name"] = []any{"old"}
+
+	src := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	src.data["tags"] = []any{[]any{"c"}}
+	src.data["name"] = []any{"new"}
+
+	if err := DeepMergeAny(dst, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(dst.data["tags"][0], []any{"a", "b", "c"}) {
+		t.Errorf("expected tags concatenated, got %v", dst.data["tags"][0])
+	}
+	if dst.data["name"][0] != "new" {
+		t.Errorf("expected scalar replaced by incoming value, got %v", dst.data["name"][0])
+	}
+}
+
+func TestDeepMergeAnyNestedMaps(t *testing.T) {
+	dst := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	dst.data["config"] = []any{map[string]any{
+		"host": "localhost",
+		"nested": map[string]any{
+			"x": 1,
+		},
+	}}
+
+	src := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	src.data["config"] = []any{map[string]any{
+		"port": 8080,
+		"nested": map[string]any{
+			"y": 2,
+		},
+	}}
+
+	if err := DeepMergeAny(dst, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	merged, ok := dst.data["config"][0].(map[string]any)
+	if !ok {
+		t.Fatalf("expected merged config to be a map[string]any, got %T", dst.data["config"][0])
+	}
+	if merged["host"] != "localhost" || merged["port"] != 8080 {
+		t.Errorf("expected both top-level keys preserved, got %v", merged)
+	}
+	nested, ok := merged["nested"].(map[string]any)
+	if !ok || nested["x"] != 1 || nested["y"] != 2 {
+		t.Errorf("expected nested maps to recursively merge, got %v", merged["nested"])
+	}
+}
+
+func TestDeepMergeAnyNewKeyAddedWholesale(t *testing.T) {
+	dst := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	src := New[string, any](func(a, b any) bool { return reflect.DeepEqual(a, b) })
+	src.data["fresh"] = []any{"value"}
+
+	if err := DeepMergeAny(dst, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if dst.data["fresh"][0] != "value" {
+		t.Errorf("expected key only in src to be added wholesale, got %v", dst.data["fresh"])
+	}
+}
+
+func TestDeepMergeAnyMultiValuedKeyErrors(t *testing.T) {
+	eq := func(a, b any) bool { return reflect.DeepEqual(a, b) }
+	dst := New[string, any](eq)
+	dst.data["a"] = []any{"one", "two"}
+	src := New[string, any](eq)
+	src.data["a"] = []any{"new"}
+
+	err := DeepMergeAny(dst, src)
+	if err == nil {
+		t.Fatal("expected an error for a multi-valued key, got nil")
+	}
+	if !reflect.DeepEqual(dst.data["a"], []any{"one", "two"}) {
+		t.Errorf("expected dst left unmodified after error, got %v", dst.data["a"])
+	}
+}
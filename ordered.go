@@ -0,0 +1,245 @@
+package multimap
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// MarshalMode selects how OrderedMultiMap.MarshalJSON encodes its contents.
+type MarshalMode int
+
+const (
+	// MarshalOrdered encodes the map as an ordered array of {key,values}
+	// pairs, preserving insertion order. This is the default.
+	MarshalOrdered MarshalMode = iota
+	// MarshalLegacyObject encodes the map as a plain JSON object
+	// (map[K][]V), matching MultiMap's wire format but losing order.
+	MarshalLegacyObject
+)
+
+// OrderedMultiMap is a thread-safe map that allows multiple values per key
+// and keeps its keys in insertion order, with support for paginated access
+// over that order. Removing a key moves the last key into the removed key's
+// position, so after a removal the order is no longer pure insertion order.
+type OrderedMultiMap[K comparable, V any] struct {
+	mu          sync.RWMutex
+	data        map[K][]V
+	order       []K
+	index       map[K]int
+	equalsFunc  func(a, b V) bool
+	MarshalMode MarshalMode
+}
+
+// orderedEntry is the JSON shape of a single key and its values, used by
+// MarshalOrdered.
+type orderedEntry[K comparable, V any] struct {
+	Key    K   `json:"key"`
+	Values []V `json:"values"`
+}
+
+// NewOrdered creates a new OrderedMultiMap with the provided value equality
+// function. It marshals in MarshalOrdered mode by default.
+func NewOrdered[K comparable, V any](equalsFunc func(a, b V) bool) *OrderedMultiMap[K, V] {
+	return &OrderedMultiMap[K, V]{
+		data:       make(map[K][]V),
+		index:      make(map[K]int),
+		equalsFunc: equalsFunc,
+	}
+}
+
+// Add inserts a value for the given key if it does not already exist (by
+// equality), recording the key's insertion position the first time it is
+// seen.
+func (m *OrderedMultiMap[K, V]) Add(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.data[key] {
+		if m.equalsFunc(v, value) {
+			return
+		}
+	}
+	if _, ok := m.index[key]; !ok {
+		m.index[key] = len(m.order)
+		m.order = append(m.order, key)
+	}
+	m.data[key] = append(m.data[key], value)
+}
+
+// Get returns a copy of the values for the given key.
+func (m *OrderedMultiMap[K, V]) Get(key K) []V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	vals := m.data[key]
+	copied := make([]V, len(vals))
+	copy(copied, vals)
+	return copied
+}
+
+// Remove deletes all values for the given key, removing it from the
+// insertion order using a swap-with-last to keep removal O(1); the moved
+// key's recorded index is updated accordingly.
+func (m *OrderedMultiMap[K, V]) Remove(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeFromOrder(key)
+	delete(m.data, key)
+}
+
+func (m *OrderedMultiMap[K, V]) removeFromOrder(key K) {
+	idx, ok := m.index[key]
+	if !ok {
+		return
+	}
+	last := len(m.order) - 1
+	if idx != last {
+		movedKey := m.order[last]
+		m.order[idx] = movedKey
+		m.index[movedKey] = idx
+	}
+	m.order = m.order[:last]
+	delete(m.index, key)
+}
+
+// RemoveValue removes a specific value for the given key (by equality). If
+// no values remain for the key, the key is removed from the map and from
+// the insertion order.
+func (m *OrderedMultiMap[K, V]) RemoveValue(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := m.data[key]
+	newValues := make([]V, 0, len(values))
+	for _, v := range values {
+		if !m.equalsFunc(v, value) {
+			newValues = append(newValues, v)
+		}
+	}
+	if len(newValues) == 0 {
+		m.removeFromOrder(key)
+		delete(m.data, key)
+	} else {
+		m.data[key] = newValues
+	}
+}
+
+// HasKey returns true if the key exists in the map.
+func (m *OrderedMultiMap[K, V]) HasKey(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.data[key]
+	return ok
+}
+
+// Len returns the number of keys in the map.
+func (m *OrderedMultiMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.order)
+}
+
+// KeysOrdered returns the keys in the map's current order: insertion order,
+// except where Remove or RemoveValue moved the last key into a removed
+// key's position.
+func (m *OrderedMultiMap[K, V]) KeysOrdered() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]K, len(m.order))
+	copy(keys, m.order)
+	return keys
+}
+
+// ForEachOrdered calls f for each key-value pair, visiting keys in
+// insertion order.
+func (m *OrderedMultiMap[K, V]) ForEachOrdered(f func(K, V)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.order {
+		for _, v := range m.data[k] {
+			f(k, v)
+		}
+	}
+}
+
+// Page returns a new OrderedMultiMap containing the keys at insertion
+// positions [pageNum*pageSize, (pageNum+1)*pageSize), in their original
+// order. An out-of-range page, a negative pageNum, or a non-positive
+// pageSize returns an empty map.
+func (m *OrderedMultiMap[K, V]) Page(pageNum, pageSize int) *OrderedMultiMap[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	page := NewOrdered[K, V](m.equalsFunc)
+	page.MarshalMode = m.MarshalMode
+
+	if pageNum < 0 || pageSize <= 0 {
+		return page
+	}
+	start := pageNum * pageSize
+	end := start + pageSize
+	if start < 0 || start >= len(m.order) {
+		return page
+	}
+	if end > len(m.order) {
+		end = len(m.order)
+	}
+	for _, k := range m.order[start:end] {
+		values := m.data[k]
+		copied := make([]V, len(values))
+		copy(copied, values)
+		page.index[k] = len(page.order)
+		page.order = append(page.order, k)
+		page.data[k] = copied
+	}
+	return page
+}
+
+// MarshalJSON implements json.Marshaler for OrderedMultiMap, encoding
+// according to m.MarshalMode.
+func (m *OrderedMultiMap[K, V]) MarshalJSON() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.MarshalMode == MarshalLegacyObject {
+		return json.Marshal(m.data)
+	}
+
+	entries := make([]orderedEntry[K, V], len(m.order))
+	for i, k := range m.order {
+		entries[i] = orderedEntry[K, V]{Key: k, Values: m.data[k]}
+	}
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for OrderedMultiMap. It accepts
+// either wire format produced by MarshalJSON: an ordered array of
+// {key,values} pairs, or a legacy plain object (whose key order is not
+// guaranteed by the encoding/json package).
+func (m *OrderedMultiMap[K, V]) UnmarshalJSON(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []orderedEntry[K, V]
+	if err := json.Unmarshal(data, &entries); err == nil {
+		m.data = make(map[K][]V, len(entries))
+		m.index = make(map[K]int, len(entries))
+		m.order = make([]K, 0, len(entries))
+		for _, e := range entries {
+			m.index[e.Key] = len(m.order)
+			m.order = append(m.order, e.Key)
+			m.data[e.Key] = e.Values
+		}
+		return nil
+	}
+
+	var raw map[K][]V
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.data = raw
+	m.index = make(map[K]int, len(raw))
+	m.order = make([]K, 0, len(raw))
+	for k := range raw {
+		m.index[k] = len(m.order)
+		m.order = append(m.order, k)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package multimap
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrderedKeysPreserveInsertionOrder(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	m.Add("c", 3)
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Add("a", 1) // duplicate value, key already present
+
+	expected := []string{"c", "a", "b"}
+	if !reflect.DeepEqual(m.KeysOrdered(), expected) {
+		t.Errorf("expected %v, got %v", expected, m.KeysOrdered())
+	}
+}
+
+func TestOrderedRemoveSwapsWithLast(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Add("c", 3)
+	m.Add("d", 4)
+
+	m.Remove("b")
+
+	expected := []string{"a", "d", "c"}
+	if !reflect.DeepEqual(m.KeysOrdered(), expected) {
+		t.Errorf("expected %v after swap-with-last removal, got %v", expected, m.KeysOrdered())
+	}
+	if m.HasKey("b") {
+		t.Errorf("expected key 'b' removed")
+	}
+}
+
+func TestOrderedRemoveValue(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.RemoveValue("a", 1)
+	if !reflect.DeepEqual(m.Get("a"), []int{2}) {
+		t.Errorf("expected a=[2], got %v", m.Get("a"))
+	}
+	m.RemoveValue("a", 2)
+	if m.HasKey("a") {
+		t.Errorf("expected key 'a' removed after last value")
+	}
+	if len(m.KeysOrdered()) != 0 {
+		t.Errorf("expected empty order, got %v", m.KeysOrdered())
+	}
+}
+
+func TestOrderedForEachOrdered(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	m.Add("b", 2)
+	m.Add("a", 1)
+
+	var keys []string
+	m.ForEachOrdered(func(k string, v int) {
+		keys = append(keys, k)
+	})
+	expected := []string{"b", "a"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
+
+func TestOrderedPage(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		m.Add(k, 1)
+	}
+
+	page0 := m.Page(0, 2)
+	if !reflect.DeepEqual(page0.KeysOrdered(), []string{"a", "b"}) {
+		t.Errorf("expected page 0 = [a b], got %v", page0.KeysOrdered())
+	}
+	page2 := m.Page(2, 2)
+	if !reflect.DeepEqual(page2.KeysOrdered(), []string{"e"}) {
+		t.Errorf("expected page 2 = [e], got %v", page2.KeysOrdered())
+	}
+	page3 := m.Page(3, 2)
+	if len(page3.KeysOrdered()) != 0 {
+		t.Errorf("expected out-of-range page to be empty, got %v", page3.KeysOrdered())
+	}
+}
+
+func TestOrderedPageNonPositiveArgsReturnEmpty(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	for _, k := range []string{"a", "b", "c"} {
+		m.Add(k, 1)
+	}
+
+	if page := m.Page(0, -1); len(page.KeysOrdered()) != 0 {
+		t.Errorf("expected negative pageSize to return an empty page, got %v", page.KeysOrdered())
+	}
+	if page := m.Page(-1, 2); len(page.KeysOrdered()) != 0 {
+		t.Errorf("expected negative pageNum to return an empty page, got %v", page.KeysOrdered())
+	}
+	if page := m.Page(-1, -2); len(page.KeysOrdered()) != 0 {
+		t.Errorf("expected negative pageNum and pageSize to return an empty page, got %v", page.KeysOrdered())
+	}
+	if page := m.Page(0, 0); len(page.KeysOrdered()) != 0 {
+		t.Errorf("expected zero pageSize to return an empty page, got %v", page.KeysOrdered())
+	}
+}
+
+func TestOrderedMarshalModes(t *testing.T) {
+	m := NewOrdered[string, int](intEquals)
+	m.Add("b", 2)
+	m.Add("a", 1)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	m2 := NewOrdered[string, int](intEquals)
+	if err := json.Unmarshal(data, m2); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if !reflect.DeepEqual(m2.KeysOrdered(), []string{"b", "a"}) {
+		t.Errorf("expected order preserved through ordered marshal round-trip, got %v", m2.KeysOrdered())
+	}
+
+	m.MarshalMode = MarshalLegacyObject
+	legacyData, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("legacy marshal error: %v", err)
+	}
+	m3 := NewOrdered[string, int](intEquals)
+	if err := json.Unmarshal(legacyData, m3); err != nil {
+		t.Fatalf("legacy unmarshal error: %v", err)
+	}
+	if !m3.HasKey("a") || !m3.HasKey("b") {
+		t.Errorf("expected legacy decode to contain both keys, got %v", m3.KeysOrdered())
+	}
+}
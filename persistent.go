@@ -0,0 +1,225 @@
+package multimap
+
+import (
+	"math/rand"
+)
+
+// PersistentMultiMap is an immutable, persistent (copy-on-write) multimap.
+// It is implemented as a treap keyed by K: every mutation returns a new
+// root while leaving the receiver untouched, and unmodified subtrees are
+// shared between the old and new trees. Snapshot on MultiMap copies the
+// source map into a new treap key by key (O(n log n) expected, not O(1),
+// and sharing no structure with the source); the result is safe to read
+// concurrently with writers mutating the source map.
+type PersistentMultiMap[K comparable, V any] struct {
+	root *treapNode[K, V]
+	less func(a, b K) bool
+}
+
+type treapNode[K comparable, V any] struct {
+	key      K
+	values   []V
+	priority int32
+	left     *treapNode[K, V]
+	right    *treapNode[K, V]
+}
+
+// NewPersistent creates an empty PersistentMultiMap ordered by less.
+func NewPersistent[K comparable, V any](less func(a, b K) bool) *PersistentMultiMap[K, V] {
+	return &PersistentMultiMap[K, V]{less: less}
+}
+
+// Snapshot atomically captures the current contents of m into a persistent
+// treap ordered by less. The returned PersistentMultiMap shares no mutable
+// state with m, so subsequent writes to m do not affect it.
+func (m *MultiMap[K, V]) Snapshot(less func(a, b K) bool) *PersistentMultiMap[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p := NewPersistent[K, V](less)
+	for k, values := range m.data {
+		copied := make([]V, len(values))
+		copy(copied, values)
+		p.root = treapSet(p.root, less, k, copied)
+	}
+	return p
+}
+
+// Get returns the values stored for key, or nil if the key is absent.
+func (p *PersistentMultiMap[K, V]) Get(key K) []V {
+	node := p.root
+	for node != nil {
+		switch {
+		case p.less(key, node.key):
+			node = node.left
+		case p.less(node.key, key):
+			node = node.right
+		default:
+			return node.values
+		}
+	}
+	return nil
+}
+
+// Len returns the number of keys stored in the tree.
+func (p *PersistentMultiMap[K, V]) Len() int {
+	return treapLen(p.root)
+}
+
+// Set returns a new PersistentMultiMap with key bound to values, leaving p
+// unmodified. An existing binding for key is replaced wholesale.
+func (p *PersistentMultiMap[K, V]) Set(key K, values []V) *PersistentMultiMap[K, V] {
+	return &PersistentMultiMap[K, V]{
+		root: treapSet(p.root, p.less, key, values),
+		less: p.less,
+	}
+}
+
+// Add returns a new PersistentMultiMap with value appended to key's values,
+// leaving p unmodified. If equalsFunc reports value already present for
+// key, Add returns p itself and allocates nothing.
+func (p *PersistentMultiMap[K, V]) Add(key K, value V, equalsFunc func(a, b V) bool) *PersistentMultiMap[K, V] {
+	existing := p.Get(key)
+	for _, v := range existing {
+		if equalsFunc(v, value) {
+			return p
+		}
+	}
+	next := make([]V, len(existing)+1)
+	copy(next, existing)
+	next[len(existing)] = value
+	return p.Set(key, next)
+}
+
+// RemoveValue returns a new PersistentMultiMap with value removed from
+// key's values (by equalsFunc), leaving p unmodified. If no values remain
+// for key, the key itself is removed.
+func (p *PersistentMultiMap[K, V]) RemoveValue(key K, value V, equalsFunc func(a, b V) bool) *PersistentMultiMap[K, V] {
+	existing := p.Get(key)
+	next := make([]V, 0, len(existing))
+	for _, v := range existing {
+		if !equalsFunc(v, value) {
+			next = append(next, v)
+		}
+	}
+	if len(next) == 0 {
+		return p.Remove(key)
+	}
+	return p.Set(key, next)
+}
+
+// Remove returns a new PersistentMultiMap with key removed, leaving p
+// unmodified.
+func (p *PersistentMultiMap[K, V]) Remove(key K) *PersistentMultiMap[K, V] {
+	return &PersistentMultiMap[K, V]{
+		root: treapRemove(p.root, p.less, key),
+		less: p.less,
+	}
+}
+
+// Iter calls f for every key-value pair in the tree, in ascending key order
+// as defined by less. Iteration stops early if f returns false.
+func (p *PersistentMultiMap[K, V]) Iter(f func(K, V) bool) {
+	treapIter(p.root, f)
+}
+
+func treapLen[K comparable, V any](n *treapNode[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return 1 + treapLen(n.left) + treapLen(n.right)
+}
+
+func treapIter[K comparable, V any](n *treapNode[K, V], f func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	if !treapIter(n.left, f) {
+		return false
+	}
+	for _, v := range n.values {
+		if !f(n.key, v) {
+			return false
+		}
+	}
+	return treapIter(n.right, f)
+}
+
+// treapSet inserts or replaces the binding for key, rotating the new leaf
+// upward while its priority exceeds its parent's, preserving the heap
+// property. Only the nodes along the search path are copied; sibling
+// subtrees are shared with the original tree.
+func treapSet[K comparable, V any](n *treapNode[K, V], less func(a, b K) bool, key K, values []V) *treapNode[K, V] {
+	if n == nil {
+		return &treapNode[K, V]{key: key, values: values, priority: rand.Int31()}
+	}
+	switch {
+	case less(key, n.key):
+		left := treapSet(n.left, less, key, values)
+		node := &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: left, right: n.right}
+		if left.priority > node.priority {
+			return rotateRight(node)
+		}
+		return node
+	case less(n.key, key):
+		right := treapSet(n.right, less, key, values)
+		node := &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: n.left, right: right}
+		if right.priority > node.priority {
+			return rotateLeft(node)
+		}
+		return node
+	default:
+		return &treapNode[K, V]{key: key, values: values, priority: n.priority, left: n.left, right: n.right}
+	}
+}
+
+// treapRemove deletes key by rotating it down until it has at most one
+// child, choosing the rotation direction by comparing the children's
+// priorities, then splicing it out (replacing it by its remaining child, if
+// any).
+func treapRemove[K comparable, V any](n *treapNode[K, V], less func(a, b K) bool, key K) *treapNode[K, V] {
+	if n == nil {
+		return nil
+	}
+	switch {
+	case less(key, n.key):
+		left := treapRemove(n.left, less, key)
+		return &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: left, right: n.right}
+	case less(n.key, key):
+		right := treapRemove(n.right, less, key)
+		return &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: n.left, right: right}
+	default:
+		return treapDelete(n)
+	}
+}
+
+func treapDelete[K comparable, V any](n *treapNode[K, V]) *treapNode[K, V] {
+	switch {
+	case n.left == nil && n.right == nil:
+		return nil
+	case n.left == nil:
+		return n.right
+	case n.right == nil:
+		return n.left
+	case n.left.priority > n.right.priority:
+		rotated := rotateRight(&treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: n.left, right: n.right})
+		rotated.right = treapDelete(rotated.right)
+		return rotated
+	default:
+		rotated := rotateLeft(&treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: n.left, right: n.right})
+		rotated.left = treapDelete(rotated.left)
+		return rotated
+	}
+}
+
+func rotateRight[K comparable, V any](n *treapNode[K, V]) *treapNode[K, V] {
+	l := n.left
+	newNode := &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: l.right, right: n.right}
+	return &treapNode[K, V]{key: l.key, values: l.values, priority: l.priority, left: l.left, right: newNode}
+}
+
+func rotateLeft[K comparable, V any](n *treapNode[K, V]) *treapNode[K, V] {
+	r := n.right
+	newNode := &treapNode[K, V]{key: n.key, values: n.values, priority: n.priority, left: n.left, right: r.left}
+	return &treapNode[K, V]{key: r.key, values: r.values, priority: r.priority, left: newNode, right: r.right}
+}
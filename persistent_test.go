@@ -0,0 +1,111 @@
+package multimap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func TestSnapshotIsolatedFromSource(t *testing.T) {
+	m := New[int, int](intEquals)
+	m.Add(1, 10)
+	m.Add(2, 20)
+
+	snap := m.Snapshot(intLess)
+	m.Add(1, 11)
+	m.Remove(2)
+
+	if !reflect.DeepEqual(snap.Get(1), []int{10}) {
+		t.Errorf("expected snapshot to retain original values for key 1, got %v", snap.Get(1))
+	}
+	if !reflect.DeepEqual(snap.Get(2), []int{20}) {
+		t.Errorf("expected snapshot to retain key 2, got %v", snap.Get(2))
+	}
+	if snap.Len() != 2 {
+		t.Errorf("expected snapshot length 2, got %d", snap.Len())
+	}
+}
+
+// TestSnapshotOnDerivedMap confirms that a MultiMap derived from another
+// one (here via Clone and Union) can still be snapshotted: Snapshot takes its ordering as a parameter, so it never
+// depends on how the receiver was constructed.
+func TestSnapshotOnDerivedMap(t *testing.T) {
+	m := New[int, int](intEquals)
+	m.Add(1, 10)
+	m.Add(2, 20)
+
+	clone := m.Clone()
+	if snap := clone.Snapshot(intLess); snap.Len() != 2 {
+		t.Errorf("expected cloned map's snapshot to have 2 keys, got %d", snap.Len())
+	}
+
+	other := New[int, int](intEquals)
+	other.Add(3, 30)
+	if snap := Union(m, other).Snapshot(intLess); snap.Len() != 3 {
+		t.Errorf("expected union's snapshot to have 3 keys, got %d", snap.Len())
+	}
+}
+
+func TestPersistentSetAddRemoveValue(t *testing.T) {
+	p := NewPersistent[int, int](intLess)
+	p1 := p.Set(1, []int{10})
+	if p.Len() != 0 {
+		t.Errorf("expected original tree to remain empty, got len %d", p.Len())
+	}
+	if p1.Len() != 1 {
+		t.Errorf("expected new tree to have 1 key, got %d", p1.Len())
+	}
+
+	p2 := p1.Add(1, 20, intEquals)
+	if !reflect.DeepEqual(p1.Get(1), []int{10}) {
+		t.Errorf("expected p1 unmodified after Add, got %v", p1.Get(1))
+	}
+	if !reflect.DeepEqual(p2.Get(1), []int{10, 20}) {
+		t.Errorf("expected p2 to have both values, got %v", p2.Get(1))
+	}
+
+	p3 := p2.RemoveValue(1, 10, intEquals)
+	if !reflect.DeepEqual(p3.Get(1), []int{20}) {
+		t.Errorf("expected p3 to have only 20, got %v", p3.Get(1))
+	}
+
+	p4 := p3.Remove(1)
+	if p4.Get(1) != nil {
+		t.Errorf("expected key 1 removed, got %v", p4.Get(1))
+	}
+	if !reflect.DeepEqual(p3.Get(1), []int{20}) {
+		t.Errorf("expected p3 to remain unmodified after deriving p4, got %v", p3.Get(1))
+	}
+}
+
+func TestPersistentIterOrder(t *testing.T) {
+	p := NewPersistent[int, int](intLess)
+	for _, k := range []int{5, 1, 3, 4, 2} {
+		p = p.Set(k, []int{k * 10})
+	}
+	var keys []int
+	p.Iter(func(k int, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	expected := []int{1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys in ascending order %v, got %v", expected, keys)
+	}
+}
+
+func TestPersistentIterEarlyStop(t *testing.T) {
+	p := NewPersistent[int, int](intLess)
+	for i := 1; i <= 5; i++ {
+		p = p.Set(i, []int{i})
+	}
+	count := 0
+	p.Iter(func(k int, v int) bool {
+		count++
+		return k < 3
+	})
+	if count != 3 {
+		t.Errorf("expected iteration to stop after 3 keys, got %d", count)
+	}
+}
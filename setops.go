@@ -0,0 +1,146 @@
+package multimap
+
+import "unsafe"
+
+// lockDstSrc locks dst for writing and src for reading without risking an
+// AB-BA deadlock against a concurrent Merge(src, dst) on another goroutine.
+// Both calls order their lock acquisition by the maps' addresses rather than
+// by dst/src role, so the two goroutines always agree on which mutex to
+// take first. It returns a function that releases both locks in reverse
+// order; dst and src being the same map is handled by taking a single Lock.
+func lockDstSrc[K comparable, V any](dst, src *MultiMap[K, V]) func() {
+	if dst == src {
+		dst.mu.Lock()
+		return dst.mu.Unlock
+	}
+	if uintptr(unsafe.Pointer(dst)) < uintptr(unsafe.Pointer(src)) {
+		dst.mu.Lock()
+		src.mu.RLock()
+		return func() {
+			src.mu.RUnlock()
+			dst.mu.Unlock()
+		}
+	}
+	src.mu.RLock()
+	dst.mu.Lock()
+	return func() {
+		dst.mu.Unlock()
+		src.mu.RUnlock()
+	}
+}
+
+// Merge copies every key-value pair from src into dst in place, respecting
+// dst's equality-based dedup. src is left unmodified.
+func Merge[K comparable, V any](dst, src *MultiMap[K, V]) {
+	unlock := lockDstSrc(dst, src)
+	defer unlock()
+
+	for k, values := range src.data {
+		for _, v := range values {
+			found := false
+			for _, existing := range dst.data[k] {
+				if dst.equalsFunc(existing, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				dst.data[k] = append(dst.data[k], v)
+			}
+		}
+	}
+}
+
+// Union returns a new MultiMap containing all key-value pairs present in
+// either a or b, deduped by a's equalsFunc.
+func Union[K comparable, V any](a, b *MultiMap[K, V]) *MultiMap[K, V] {
+	result := a.Clone()
+	Merge(result, b)
+	return result
+}
+
+// Intersect returns a new MultiMap containing only the key-value pairs
+// present in both a and b, by a's equalsFunc.
+func Intersect[K comparable, V any](a, b *MultiMap[K, V]) *MultiMap[K, V] {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := New[K, V](a.equalsFunc)
+	for k, values := range a.data {
+		bValues, ok := b.data[k]
+		if !ok {
+			continue
+		}
+		for _, v := range values {
+			for _, bv := range bValues {
+				if a.equalsFunc(v, bv) {
+					result.data[k] = append(result.data[k], v)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Difference returns a new MultiMap containing the key-value pairs present
+// in a but not in b, by a's equalsFunc.
+func Difference[K comparable, V any](a, b *MultiMap[K, V]) *MultiMap[K, V] {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := New[K, V](a.equalsFunc)
+	for k, values := range a.data {
+		bValues := b.data[k]
+		for _, v := range values {
+			inB := false
+			for _, bv := range bValues {
+				if a.equalsFunc(v, bv) {
+					inB = true
+					break
+				}
+			}
+			if !inB {
+				result.data[k] = append(result.data[k], v)
+			}
+		}
+	}
+	return result
+}
+
+// Filter returns a new MultiMap containing only the key-value pairs for
+// which pred returns true.
+func (m *MultiMap[K, V]) Filter(pred func(K, V) bool) *MultiMap[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := New[K, V](m.equalsFunc)
+	for k, values := range m.data {
+		for _, v := range values {
+			if pred(k, v) {
+				result.data[k] = append(result.data[k], v)
+			}
+		}
+	}
+	return result
+}
+
+// MapValues returns a new MultiMap[K, W] obtained by applying f to every
+// value of m, using eq as the new map's equality function and deduping
+// results per key as they are inserted.
+func MapValues[K comparable, V, W any](m *MultiMap[K, V], eq func(a, b W) bool, f func(K, V) W) *MultiMap[K, W] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := New[K, W](eq)
+	for k, values := range m.data {
+		for _, v := range values {
+			result.Add(k, f(k, v))
+		}
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package multimap
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	dst := New[string, int](intEquals)
+	dst.Add("a", 1)
+	src := New[string, int](intEquals)
+	src.Add("a", 1)
+	src.Add("a", 2)
+	src.Add("b", 3)
+
+	Merge(dst, src)
+
+	if !reflect.DeepEqual(dst.Get("a"), []int{1, 2}) {
+		t.Errorf("expected a=[1,2], got %v", dst.Get("a"))
+	}
+	if !reflect.DeepEqual(dst.Get("b"), []int{3}) {
+		t.Errorf("expected b=[3], got %v", dst.Get("b"))
+	}
+	if !reflect.DeepEqual(src.Get("a"), []int{1, 2}) {
+		t.Errorf("expected src unmodified, got %v", src.Get("a"))
+	}
+}
+
+// TestMergeOppositeDirectionsDoNotDeadlock is a regression test for an
+// AB-BA deadlock: Merge used to always RLock src then Lock dst, so
+// Merge(a, b) running concurrently with Merge(b, a) could have one
+// goroutine holding b's RLock while waiting on a's Lock, and the other
+// holding a's RLock while waiting on b's Lock. Merge now orders lock
+// acquisition by map address instead of by dst/src role, so both
+// goroutines agree on which mutex to take first.
+func TestMergeOppositeDirectionsDoNotDeadlock(t *testing.T) {
+	a := New[string, int](intEquals)
+	a.Add("a", 1)
+	b := New[string, int](intEquals)
+	b.Add("b", 2)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				Merge(a, b)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				Merge(b, a)
+			}
+		}()
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Merge(a, b) and Merge(b, a) deadlocked")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	a := New[string, int](intEquals)
+	a.Add("a", 1)
+	b := New[string, int](intEquals)
+	b.Add("a", 2)
+	b.Add("b", 3)
+
+	u := Union(a, b)
+	if !reflect.DeepEqual(u.Get("a"), []int{1, 2}) {
+		t.Errorf("expected a=[1,2], got %v", u.Get("a"))
+	}
+	if !reflect.DeepEqual(u.Get("b"), []int{3}) {
+		t.Errorf("expected b=[3], got %v", u.Get("b"))
+	}
+	if a.HasKey("b") {
+		t.Errorf("expected a unmodified")
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	a := New[string, int](intEquals)
+	a.Add("a", 1)
+	a.Add("a", 2)
+	a.Add("b", 3)
+	b := New[string, int](intEquals)
+	b.Add("a", 2)
+	b.Add("c", 4)
+
+	i := Intersect(a, b)
+	if !reflect.DeepEqual(i.Get("a"), []int{2}) {
+		t.Errorf("expected a=[2], got %v", i.Get("a"))
+	}
+	if i.HasKey("b") || i.HasKey("c") {
+		t.Errorf("expected only key 'a' in intersection, got %v", i.Keys())
+	}
+}
+
+func TestDifference(t *testing.T) {
+	a := New[string, int](intEquals)
+	a.Add("a", 1)
+	a.Add("a", 2)
+	a.Add("b", 3)
+	b := New[string, int](intEquals)
+	b.Add("a", 2)
+
+	d := Difference(a, b)
+	if !reflect.DeepEqual(d.Get("a"), []int{1}) {
+		t.Errorf("expected a=[1], got %v", d.Get("a"))
+	}
+	if !reflect.DeepEqual(d.Get("b"), []int{3}) {
+		t.Errorf("expected b=[3], got %v", d.Get("b"))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	m := New[string, int](intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("b", 3)
+
+	even := m.Filter(func(k string, v int) bool { return v%2 == 0 })
+	if !reflect.DeepEqual(even.Get("a"), []int{2}) {
+		t.Errorf("expected a=[2], got %v", even.Get("a"))
+	}
+	if even.HasKey("b") {
+		t.Errorf("expected key 'b' to be filtered out")
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	m := New[string, int](intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("b", 3)
+
+	strs := MapValues(m, func(a, b string) bool { return a == b }, func(k string, v int) string {
+		if v%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	})
+
+	vals := strs.Get("a")
+	sort.Strings(vals)
+	if !reflect.DeepEqual(vals, []string{"even", "odd"}) {
+		t.Errorf("expected a=[even,odd], got %v", vals)
+	}
+}
@@ -0,0 +1,288 @@
+package multimap
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+)
+
+// DefaultShardCount is the number of shards used by NewSharded when the
+// caller does not need to tune it.
+const DefaultShardCount = 32
+
+// ShardedMultiMap is a thread-safe map that allows multiple values per key,
+// like MultiMap, but spreads keys across N independent shards so that
+// operations on different shards do not contend on a single mutex.
+// K is the key type (must be comparable), V is the value type.
+type ShardedMultiMap[K comparable, V any] struct {
+	shards     []*shard[K, V]
+	hashFunc   func(K) uint64
+	equalsFunc func(a, b V) bool
+}
+
+type shard[K comparable, V any] struct {
+	mu   sync.RWMutex
+	data map[K][]V
+}
+
+// NewSharded creates a new ShardedMultiMap with the given number of shards,
+// a hash function used to route keys to shards, and a value equality
+// function. If shardCount is <= 0, DefaultShardCount is used.
+func NewSharded[K comparable, V any](shardCount int, hashFunc func(K) uint64, equalsFunc func(a, b V) bool) *ShardedMultiMap[K, V] {
+	if shardCount <= 0 {
+		shardCount = DefaultShardCount
+	}
+	shards := make([]*shard[K, V], shardCount)
+	for i := range shards {
+		shards[i] = &shard[K, V]{data: make(map[K][]V)}
+	}
+	return &ShardedMultiMap[K, V]{
+		shards:     shards,
+		hashFunc:   hashFunc,
+		equalsFunc: equalsFunc,
+	}
+}
+
+// HashString hashes a string key using fnv-1a. It is suitable for use as the
+// hashFunc argument to NewSharded when K is string.
+func HashString(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// HashInt hashes an int key using fnv-1a over its 8 big-endian bytes. It is
+// suitable for use as the hashFunc argument to NewSharded when K is int.
+func HashInt(key int) uint64 {
+	buf := [8]byte{
+		byte(key >> 56), byte(key >> 48), byte(key >> 40), byte(key >> 32),
+		byte(key >> 24), byte(key >> 16), byte(key >> 8), byte(key),
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(buf[:])
+	return h.Sum64()
+}
+
+// HashBytes hashes a []byte key using fnv-1a. It is suitable for use as the
+// hashFunc argument to NewSharded when K is []byte (wrapped in a comparable
+// key type by the caller, since []byte is not itself comparable).
+func HashBytes(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	return h.Sum64()
+}
+
+func (m *ShardedMultiMap[K, V]) shardFor(key K) *shard[K, V] {
+	idx := m.hashFunc(key) % uint64(len(m.shards))
+	return m.shards[idx]
+}
+
+// Add inserts a value for the given key if it does not already exist (by equality).
+func (m *ShardedMultiMap[K, V]) Add(key K, value V) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.data[key] {
+		if m.equalsFunc(v, value) {
+			return
+		}
+	}
+	s.data[key] = append(s.data[key], value)
+}
+
+// Get returns a copy of the values for the given key.
+// If the key does not exist, returns an empty slice.
+func (m *ShardedMultiMap[K, V]) Get(key K) []V {
+	s := m.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	vals := s.data[key]
+	copied := make([]V, len(vals))
+	copy(copied, vals)
+	return copied
+}
+
+// Remove deletes all values for the given key.
+func (m *ShardedMultiMap[K, V]) Remove(key K) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+// RemoveValue removes a specific value for the given key (by equality).
+// If no values remain for the key, the key is removed.
+func (m *ShardedMultiMap[K, V]) RemoveValue(key K, value V) {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := s.data[key]
+	newValues := make([]V, 0, len(values))
+	for _, v := range values {
+		if !m.equalsFunc(v, value) {
+			newValues = append(newValues, v)
+		}
+	}
+	if len(newValues) == 0 {
+		delete(s.data, key)
+	} else {
+		s.data[key] = newValues
+	}
+}
+
+// Has returns true if the given value exists for the key (by equality).
+func (m *ShardedMultiMap[K, V]) Has(key K, value V) bool {
+	s := m.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.data[key] {
+		if m.equalsFunc(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasKey returns true if the key exists in the map.
+func (m *ShardedMultiMap[K, V]) HasKey(key K) bool {
+	s := m.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.data[key]
+	return ok
+}
+
+// Keys returns a slice of all keys in the map, gathered by taking a read
+// lock on each shard in turn.
+func (m *ShardedMultiMap[K, V]) Keys() []K {
+	keys := make([]K, 0)
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for k := range s.data {
+			keys = append(keys, k)
+		}
+		s.mu.RUnlock()
+	}
+	return keys
+}
+
+// Len returns the number of keys in the map.
+func (m *ShardedMultiMap[K, V]) Len() int {
+	count := 0
+	for _, s := range m.shards {
+		s.mu.RLock()
+		count += len(s.data)
+		s.mu.RUnlock()
+	}
+	return count
+}
+
+// Count returns the total number of values across all keys.
+func (m *ShardedMultiMap[K, V]) Count() int {
+	count := 0
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for _, values := range s.data {
+			count += len(values)
+		}
+		s.mu.RUnlock()
+	}
+	return count
+}
+
+// Clear removes all keys and values from the map.
+func (m *ShardedMultiMap[K, V]) Clear() {
+	for _, s := range m.shards {
+		s.mu.Lock()
+		s.data = make(map[K][]V)
+		s.mu.Unlock()
+	}
+}
+
+// ForEach calls the provided function for each key-value pair. Each shard is
+// iterated under its own read lock, so a writer on one shard cannot block
+// the traversal of another.
+func (m *ShardedMultiMap[K, V]) ForEach(f func(K, V)) {
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for k, values := range s.data {
+			for _, v := range values {
+				f(k, v)
+			}
+		}
+		s.mu.RUnlock()
+	}
+}
+
+// Clone returns a deep copy of the ShardedMultiMap, preserving the shard
+// count, hash function and equality function.
+func (m *ShardedMultiMap[K, V]) Clone() *ShardedMultiMap[K, V] {
+	clone := NewSharded[K, V](len(m.shards), m.hashFunc, m.equalsFunc)
+	for i, s := range m.shards {
+		s.mu.RLock()
+		for k, values := range s.data {
+			copied := make([]V, len(values))
+			copy(copied, values)
+			clone.shards[i].data[k] = copied
+		}
+		s.mu.RUnlock()
+	}
+	return clone
+}
+
+// EqualSharded returns true if the two ShardedMultiMaps contain the same
+// keys and values (by equality), regardless of how they are sharded.
+func EqualSharded[K comparable, V any](a, b *ShardedMultiMap[K, V]) bool {
+	if a.Count() != b.Count() || a.Len() != b.Len() {
+		return false
+	}
+	equal := true
+	a.ForEach(func(k K, v V) {
+		if !equal {
+			return
+		}
+		if !b.Has(k, v) {
+			equal = false
+		}
+	})
+	return equal
+}
+
+// Equal returns true if the other ShardedMultiMap contains the same keys
+// and values (by equality).
+func (m *ShardedMultiMap[K, V]) Equal(other *ShardedMultiMap[K, V]) bool {
+	return EqualSharded(m, other)
+}
+
+// MarshalJSON implements json.Marshaler for ShardedMultiMap, encoding it as
+// a plain map[K][]V, the same shape MultiMap marshals to.
+func (m *ShardedMultiMap[K, V]) MarshalJSON() ([]byte, error) {
+	merged := make(map[K][]V)
+	for _, s := range m.shards {
+		s.mu.RLock()
+		for k, values := range s.data {
+			copied := make([]V, len(values))
+			copy(copied, values)
+			merged[k] = copied
+		}
+		s.mu.RUnlock()
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ShardedMultiMap. The
+// ShardedMultiMap must already have been created via NewSharded so that its
+// shards, hashFunc and equalsFunc are initialized.
+func (m *ShardedMultiMap[K, V]) UnmarshalJSON(data []byte) error {
+	var raw map[K][]V
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, values := range raw {
+		s := m.shardFor(k)
+		s.mu.Lock()
+		s.data[k] = values
+		s.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package multimap
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestShardedAddAndGet(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("a", 1) // duplicate, should not be added
+
+	vals := m.Get("a")
+	expected := []int{1, 2}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected %v, got %v", expected, vals)
+	}
+}
+
+func TestShardedRemove(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("b", 2)
+	m.Remove("a")
+	if m.HasKey("a") {
+		t.Errorf("expected key 'a' to be removed")
+	}
+	if !m.HasKey("b") {
+		t.Errorf("expected key 'b' to exist")
+	}
+}
+
+func TestShardedRemoveValue(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.RemoveValue("a", 1)
+	vals := m.Get("a")
+	expected := []int{2}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected %v, got %v", expected, vals)
+	}
+	m.RemoveValue("a", 2)
+	if m.HasKey("a") {
+		t.Errorf("expected key 'a' to be removed after last value removed")
+	}
+}
+
+func TestShardedKeysLenCount(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("b", 3)
+	if m.Len() != 2 {
+		t.Errorf("expected 2 keys, got %d", m.Len())
+	}
+	if m.Count() != 3 {
+		t.Errorf("expected 3 values, got %d", m.Count())
+	}
+	keyMap := map[string]bool{}
+	for _, k := range m.Keys() {
+		keyMap[k] = true
+	}
+	if !keyMap["a"] || !keyMap["b"] {
+		t.Errorf("expected keys 'a' and 'b', got %v", m.Keys())
+	}
+}
+
+func TestShardedClearAndForEach(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("b", 3)
+	result := map[string][]int{}
+	m.ForEach(func(k string, v int) {
+		result[k] = append(result[k], v)
+	})
+	if len(result["a"]) != 2 || len(result["b"]) != 1 {
+		t.Errorf("unexpected result from ForEach: %v", result)
+	}
+	m.Clear()
+	if m.Len() != 0 {
+		t.Errorf("expected map to be cleared")
+	}
+}
+
+func TestShardedCloneAndEqual(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("b", 2)
+	clone := m.Clone()
+	if !m.Equal(clone) {
+		t.Errorf("expected clone to be equal to original")
+	}
+	clone.Add("a", 3)
+	if m.Equal(clone) {
+		t.Errorf("expected clone to differ after modification")
+	}
+}
+
+func TestShardedMarshalUnmarshalJSON(t *testing.T) {
+	m := NewSharded[string, int](4, HashString, intEquals)
+	m.Add("a", 1)
+	m.Add("a", 2)
+	m.Add("b", 3)
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	m2 := NewSharded[string, int](4, HashString, intEquals)
+	if err := json.Unmarshal(data, m2); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if !m.Equal(m2) {
+		t.Errorf("expected unmarshaled sharded multimap to equal original")
+	}
+}
+
+func TestShardedDefaultShardCount(t *testing.T) {
+	m := NewSharded[string, int](0, HashString, intEquals)
+	if len(m.shards) != DefaultShardCount {
+		t.Errorf("expected %d shards, got %d", DefaultShardCount, len(m.shards))
+	}
+}
+
+func benchmarkShardedMixed(b *testing.B, goroutines int) {
+	m := NewSharded[int, int](DefaultShardCount, HashInt, intEquals)
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < b.N/goroutines+1; i++ {
+				key := (g*1000 + i) % 1024
+				m.Add(key, i)
+				m.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func benchmarkMultiMapMixed(b *testing.B, goroutines int) {
+	m := New[int, int](intEquals)
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < b.N/goroutines+1; i++ {
+				key := (g*1000 + i) % 1024
+				m.Add(key, i)
+				m.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkMixedWorkload(b *testing.B) {
+	for _, goroutines := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("Sharded/%dg", goroutines), func(b *testing.B) {
+			benchmarkShardedMixed(b, goroutines)
+		})
+		b.Run(fmt.Sprintf("MultiMap/%dg", goroutines), func(b *testing.B) {
+			benchmarkMultiMapMixed(b, goroutines)
+		})
+	}
+}